@@ -11,50 +11,151 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
-	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ChimeraCoder/anaconda"
 	_ "github.com/lib/pq"
+
+	"github.com/vlazzle/BuoyBot/httpserver"
+	"github.com/vlazzle/BuoyBot/scheduler"
+	"github.com/vlazzle/BuoyBot/sources"
+	"github.com/vlazzle/BuoyBot/stats"
+	"github.com/vlazzle/BuoyBot/storage"
+)
+
+// Default cron specs used when a station doesn't set its own. FetchSchedule
+// runs every ten minutes; TweetSchedule reproduces BuoyBot's original
+// tweet-only-at-these-hours behavior.
+const (
+	defaultFetchSchedule = "*/10 * * * *"
+	defaultTweetSchedule = "0 5,7,9,11,13,16,18,20 * * *"
+	defaultTimeZone      = "US/Pacific"
+	defaultLookbackHours = 24
+	// defaultDigestLookbackHours is the trend window a digest tweet
+	// summarizes over, independent of LookbackHours (which sizes the trend
+	// line appended to regular tweets).
+	defaultDigestLookbackHours = 24 * 7
+
+	// httpJSONSourceName selects the generic HTTP JSON adapter, configured
+	// per-station via StationConfig.HTTPJSON rather than being a
+	// self-registering sources.Source like the NDBC/CO-OPS adapters.
+	httpJSONSourceName = "http-json"
 )
 
-// URL format for SF Buoy Observations
-const noaaURLFmt = "http://www.ndbc.noaa.gov/data/realtime2/%s.txt"
-
-// Observation struct stores buoy observation data
-type Observation struct {
-	Date                  time.Time
-	SignificantWaveHeight float64
-	DominantWavePeriod    int
-	AveragePeriod         float64
-	MeanWaveDirection     string
-	WaterTemperature      float64
+// StationConfig names one data source to poll, the station/buoy ID to pass
+// to it, which metrics to extract from the resulting Observation, and how
+// often to fetch/tweet/digest it. HTTPJSON configures the generic HTTP
+// JSON adapter and is only read when Source is "http-json".
+type StationConfig struct {
+	Source              string                  `json:"Source"`
+	StationId           string                  `json:"StationId"`
+	Metrics             []string                `json:"Metrics"`
+	TimeZone            string                  `json:"TimeZone"`
+	FetchSchedule       string                  `json:"FetchSchedule"`
+	TweetSchedule       string                  `json:"TweetSchedule"`
+	DigestSchedule      string                  `json:"DigestSchedule"`
+	LookbackHours       int                     `json:"LookbackHours"`
+	DigestLookbackHours int                     `json:"DigestLookbackHours"`
+	HTTPJSON            *sources.HTTPJSONSource `json:"HTTPJSON"`
+}
+
+// lookback returns the station's configured trend lookback window,
+// defaulting to defaultLookbackHours when unset.
+func (s StationConfig) lookback() time.Duration {
+	hours := s.LookbackHours
+	if hours == 0 {
+		hours = defaultLookbackHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// digestLookback returns the station's configured digest trend window,
+// defaulting to defaultDigestLookbackHours when unset.
+func (s StationConfig) digestLookback() time.Duration {
+	hours := s.DigestLookbackHours
+	if hours == 0 {
+		hours = defaultDigestLookbackHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// location returns the station's configured time zone, defaulting to
+// defaultTimeZone when unset.
+func (s StationConfig) location() *time.Location {
+	name := s.TimeZone
+	if name == "" {
+		name = defaultTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatal("Error loading location for station", s.StationId, ":", err)
+	}
+	return loc
 }
 
-// Config struct stores Twitter and Database credentials and buoy ID
+// Config struct stores Twitter and Database credentials and the stations to poll
 type Config struct {
-	UserName         string `json:"UserName"`
-	ConsumerKey      string `json:"ConsumerKey"`
-	ConsumerSecret   string `json:"ConsumerSecret"`
-	Token            string `json:"Token"`
-	TokenSecret      string `json:"TokenSecret"`
-	DatabaseFile     string `json:"DatabaseFile"`
-	BuoyId           string `json:"BuoyId"`
+	UserName       string                `json:"UserName"`
+	ConsumerKey    string                `json:"ConsumerKey"`
+	ConsumerSecret string                `json:"ConsumerSecret"`
+	Token          string                `json:"Token"`
+	TokenSecret    string                `json:"TokenSecret"`
+	DatabaseFile   string                `json:"DatabaseFile"`
+	Stations       []StationConfig       `json:"Stations"`
+	Influx         *storage.ClientConfig `json:"Influx"`
 }
 
 // Variable for database
 var db *sql.DB
 
+// Variable for the storage backend(s) observations are written to
+var writer storage.Writer
+
+// Variable for the Prometheus metrics shared between the fetch/tweet loop
+// and the optional HTTP service
+var metrics = httpserver.NewMetrics()
+
+// influxConfigFromEnv builds an Influx ClientConfig from INFLUX_URL/
+// INFLUX_TOKEN/INFLUX_DATABASE, falling back to cfg (the config.json
+// Influx block) for any value not set in the environment. Returns nil if
+// neither source names a URL, meaning Influx writing is disabled.
+func influxConfigFromEnv(cfg *storage.ClientConfig) *storage.ClientConfig {
+	result := storage.ClientConfig{}
+	if cfg != nil {
+		result = *cfg
+	}
+	if v := os.Getenv("INFLUX_URL"); v != "" {
+		result.URL = v
+	}
+	if v := os.Getenv("INFLUX_TOKEN"); v != "" {
+		result.Token = v
+	}
+	if v := os.Getenv("INFLUX_DATABASE"); v != "" {
+		result.Database = v
+	}
+	if result.URL == "" {
+		return nil
+	}
+	return &result
+}
+
 func main() {
+	runOnce := flag.Bool("once", false, "fetch and save each configured station immediately, tweeting only at the default tweet hours, then exit, instead of running the scheduler")
+	serve := flag.Bool("serve", false, "also serve observations and metrics over HTTP (see SERVE_ADDR)")
+	flag.Parse()
+
 	fmt.Println("Starting BuoyBot...")
 
 	// Load configuration
@@ -75,49 +176,192 @@ func main() {
 		log.Fatal("Error: Could not establish connection with the database.", err)
 	}
 
-	// Get latest observation and store in struct
-	var observation Observation
-	observation = getObservation(config.BuoyId)
+	// Build the storage fan-out: Postgres always, InfluxDB v3 in addition
+	// when configured via env vars or the config.json Influx block.
+	writers := storage.MultiWriter{storage.NewPostgresWriter(db)}
+	if influxCfg := influxConfigFromEnv(config.Influx); influxCfg != nil {
+		influxWriter, err := storage.NewInfluxWriter(*influxCfg)
+		if err != nil {
+			log.Fatal("Error configuring InfluxDB writer:", err)
+		}
+		defer influxWriter.Close()
+		writers = append(writers, influxWriter)
+	}
+	writer = writers
 
-	// Save latest observation in database
-	saveObservation(observation)
+	if addr := os.Getenv("SERVE_ADDR"); *serve || addr != "" {
+		if addr == "" {
+			addr = ":8080"
+		}
+		server := httpserver.NewServer(db, metrics)
+		go func() {
+			log.Println("Serving observations and metrics on", addr)
+			if err := http.ListenAndServe(addr, server.Handler()); err != nil {
+				log.Fatal("Error running HTTP server:", err)
+			}
+		}()
+	}
 
-	// Format observation given Observation
-	observationOutput := formatObservation(observation)
+	if *runOnce {
+		runAllStationsOnce(config)
+		fmt.Println("Exiting BuoyBot...")
+		return
+	}
 
-	// Tweet observation at 0000, 0600, 0800, 1000, 1200, 1400, 1600|| 1800 PST
-	var loc *time.Location
-	loc, err = time.LoadLocation("US/Pacific")
-	if err != nil {
-		log.Fatal("Error loading location:", err)
+	runScheduled(config)
+}
+
+// runAllStationsOnce reproduces BuoyBot's original one-shot behavior: fetch
+// and save every station, tweeting only those whose default update hours
+// match the current time in their own time zone.
+func runAllStationsOnce(config Config) {
+	for _, station := range config.Stations {
+		t := time.Now().In(station.location())
+		fmt.Println(station.StationId, t)
+		atUpdateInterval := t.Hour() == 5 || t.Hour() == 7 || t.Hour() == 9 || t.Hour() == 11 || t.Hour() == 13 || t.Hour() == 16 || t.Hour() == 18 || t.Hour() == 20
+		if atUpdateInterval {
+			fetchSaveAndTweet(config, station)
+		} else {
+			fmt.Println("Not at update interval - not tweeting.")
+			fetchAndSave(station)
+		}
 	}
+}
 
-	t := time.Now().In(loc)
-	fmt.Println(t)
-	if t.Hour() == 5 || t.Hour() == 7 || t.Hour() == 9 || t.Hour() == 11 || t.Hour() == 13 || t.Hour() == 16 || t.Hour() == 18 || t.Hour() == 20 {
-		tweetCurrent(config, observationOutput)
-	} else {
-		fmt.Println("Not at update interval - not tweeting.")
-		fmt.Println(observationOutput)
+// runScheduled starts a cron job per station per action (fetch, tweet) and
+// blocks until BuoyBot receives SIGINT/SIGTERM, at which point it waits for
+// any in-flight jobs to finish before returning.
+func runScheduled(config Config) {
+	s := scheduler.New()
+
+	for _, station := range config.Stations {
+		station := station
+		loc := station.location()
+
+		fetchSpec := station.FetchSchedule
+		if fetchSpec == "" {
+			fetchSpec = defaultFetchSchedule
+		}
+		if err := s.AddJob("fetch:"+station.StationId, fetchSpec, loc, func() {
+			fetchAndSave(station)
+		}); err != nil {
+			log.Fatal(err)
+		}
+
+		tweetSpec := station.TweetSchedule
+		if tweetSpec == "" {
+			tweetSpec = defaultTweetSchedule
+		}
+		if err := s.AddJob("tweet:"+station.StationId, tweetSpec, loc, func() {
+			fetchSaveAndTweet(config, station)
+		}); err != nil {
+			log.Fatal(err)
+		}
+
+		if station.DigestSchedule != "" {
+			if err := s.AddJob("digest:"+station.StationId, station.DigestSchedule, loc, func() {
+				tweetDigest(config, station)
+			}); err != nil {
+				log.Fatal(err)
+			}
+		}
 	}
 
-	// Shutdown BuoyBot
+	s.Start()
+	fmt.Println("BuoyBot scheduler running. Press Ctrl+C to stop.")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	fmt.Println("Shutting down BuoyBot scheduler...")
+	s.Stop()
 	fmt.Println("Exiting BuoyBot...")
 }
 
-// Fetches and parses latest NBDC observation and returns data in Observation struct
-func getObservation(buoyId string) Observation {
-	var noaaURL = fmt.Sprintf(noaaURLFmt, buoyId)
-	observationRaw := getDataFromURL(noaaURL)
-	observationData := parseData(observationRaw)
-	return observationData
+// fetchAndSave fetches a station's latest observation and persists it,
+// without tweeting. Used for sub-hourly DB backfill between tweets.
+func fetchAndSave(station StationConfig) {
+	observation, err := getObservation(station)
+	if err != nil {
+		fmt.Println("Error fetching observation for station", station.StationId, ":", err)
+		metrics.FetchFailures.Inc()
+		return
+	}
+	metrics.FetchSuccesses.Inc()
+	metrics.RecordObservation(observation)
+	saveObservation(observation)
+}
+
+// fetchSaveAndTweet fetches a station's latest observation, persists it,
+// and tweets it along with a short-term trend line when enough history has
+// accumulated.
+func fetchSaveAndTweet(config Config, station StationConfig) {
+	observation, err := getObservation(station)
+	if err != nil {
+		fmt.Println("Error fetching observation for station", station.StationId, ":", err)
+		metrics.FetchFailures.Inc()
+		return
+	}
+	metrics.FetchSuccesses.Inc()
+	metrics.RecordObservation(observation)
+	saveObservation(observation)
+
+	output := formatObservation(observation, station.location())
+	if trend, ok, err := stats.ComputeSwellTrend(context.Background(), db, station.StationId, station.lookback()); err != nil {
+		fmt.Println("Error computing swell trend for station", station.StationId, ":", err)
+	} else if ok {
+		output += "\n" + trend.String()
+	}
+
+	tweetCurrent(config, output)
 }
 
-// Given Observation struct, saves most recent observation in database
-func saveObservation(o Observation) {
-	_, err := db.Exec("INSERT INTO observations(observationtime, significantwaveheight, dominantwaveperiod, averageperiod, meanwavedirection, watertemperature) VALUES($1, $2, $3, $4, $5, $6)", o.Date, o.SignificantWaveHeight, o.DominantWavePeriod, o.AveragePeriod, o.MeanWaveDirection, o.WaterTemperature)
+// tweetDigest tweets a summary of stored history over the station's digest
+// lookback window, independent of any fresh fetch - it's a periodic recap
+// (e.g. weekly) rather than a point-in-time reading.
+func tweetDigest(config Config, station StationConfig) {
+	trend, ok, err := stats.ComputeSwellTrend(context.Background(), db, station.StationId, station.digestLookback())
 	if err != nil {
-		log.Fatal("Error saving observation:", err)
+		fmt.Println("Error computing digest trend for station", station.StationId, ":", err)
+		return
+	}
+	if !ok {
+		fmt.Println("Not enough history yet for a digest for station", station.StationId)
+		return
+	}
+	tweetCurrent(config, fmt.Sprintf("%s digest\n%s", station.StationId, trend.String()))
+}
+
+// Fetches the latest observation for a station using its configured Source
+// adapter, filtered down to the station's configured Metrics (sensor keys
+// from the sources.Sensor* constants; an empty list keeps everything the
+// adapter returns).
+func getObservation(station StationConfig) (sources.Observation, error) {
+	if station.Source == httpJSONSourceName && station.HTTPJSON != nil {
+		o, err := station.HTTPJSON.Fetch(station.StationId)
+		if err != nil {
+			return sources.Observation{}, err
+		}
+		return o.Filter(station.Metrics), nil
+	}
+
+	source, err := sources.Get(station.Source)
+	if err != nil {
+		return sources.Observation{}, err
+	}
+	o, err := source.Fetch(station.StationId)
+	if err != nil {
+		return sources.Observation{}, err
+	}
+	return o.Filter(station.Metrics), nil
+}
+
+// Given Observation, saves it to every configured storage backend
+func saveObservation(o sources.Observation) {
+	if err := writer.Write(context.Background(), o); err != nil {
+		fmt.Println("Error saving observation for station", o.StationID, ":", err)
+		metrics.SaveFailures.Inc()
 	}
 }
 
@@ -134,24 +378,10 @@ func tweetCurrent(config Config, o string) {
 	} else {
 		fmt.Println("Tweet posted:")
 		fmt.Println(tweet.Text)
+		metrics.TweetsPosted.Inc()
 	}
 }
 
-// Given URL, returns raw data with recent observations from NBDC
-func getDataFromURL(url string) (body []byte) {
-	resp, err := http.Get(url)
-	if err != nil {
-		log.Fatal("Error fetching data:", err)
-	}
-	defer resp.Body.Close()
-	body, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal("ioutil error reading resp.Body:", err)
-	}
-	// fmt.Println("Status:", resp.Status)
-	return
-}
-
 // Given path to config.js file, loads credentials
 func loadConfig(config *Config) {
 	// Load path to config from CONFIGPATH environment variable
@@ -168,64 +398,25 @@ func loadConfig(config *Config) {
 	}
 }
 
-// Given raw data, parses latest observation and returns Observation struct
-func parseData(d []byte) Observation {
-	// Each line contains 19 data points
-	// Headers are in the first two lines
-	// Latest observation data is in the third line
-	// Other lines are not needed
-
-	// Extracts relevant data into variable for processing
-	var data = string(d[188:281])
-	// Convert most recent observation into array of strings
-	datafield := strings.Fields(data)
-
-	// Convert wave height from meters to feet
-	waveheightmeters, _ := strconv.ParseFloat(datafield[8], 64)
-	waveheightfeet := waveheightmeters * 3.28084
-
-	// Convert wave direction from degrees to cardinal
-	wavedegrees, _ := strconv.ParseInt(datafield[11], 0, 64)
-	wavecardinal := direction(wavedegrees)
-
-	// Convert water temp from C to F
-	watertempC, _ := strconv.ParseFloat(datafield[14], 64)
-	watertempF := watertempC*9/5 + 32
-	watertempF = RoundPlus(watertempF, 1)
-
-	// Process date/time and convert to PST
-	rawtime := strings.Join(datafield[0:5], " ")
-	t, err := time.Parse("2006 01 02 15 04", rawtime)
-	if err != nil {
-		log.Fatal("error processing rawtime:", err)
+// Given Observation, returns formatted text for tweet, with the observation
+// time localized to loc (the station's own configured time zone). Falls
+// back to a generic sensor listing when the familiar swell/water-temp
+// fields aren't all present (e.g. a tide-only or spectral-only station).
+func formatObservation(o sources.Observation, loc *time.Location) string {
+	waveHeight, hasWave := o.Value(sources.SensorWaveHeightFt)
+	period, hasPeriod := o.Value(sources.SensorDominantPeriodSec)
+	degrees, hasDirection := o.Value(sources.SensorMeanWaveDirectionDeg)
+	waterTemp, hasTemp := o.Value(sources.SensorWaterTempF)
+
+	localDate := o.Date.In(loc)
+	if hasWave && hasPeriod && hasDirection && hasTemp {
+		return fmt.Sprint(localDate.Format(time.RFC822), "\nSwell: ", strconv.FormatFloat(waveHeight, 'f', 1, 64), "ft at ", int(period), " sec from ", direction(int64(degrees)), "\n", "Water: ", RoundPlus(waterTemp, 1), "F")
 	}
-	loc, err := time.LoadLocation("America/Los_Angeles")
-	if err != nil {
-		log.Fatal("error processing location", err)
-	}
-	t = t.In(loc)
 
-	// Create Observation struct and populate with parsed data
-	var o Observation
-	o.Date = t
-	o.SignificantWaveHeight = waveheightfeet
-	o.DominantWavePeriod, err = strconv.Atoi(datafield[9])
-	if err != nil {
-		log.Fatal("o.AveragePeriod:", err)
+	output := localDate.Format(time.RFC822)
+	for sensor, value := range o.Sensors {
+		output += fmt.Sprintf("\n%s: %s", sensor, strconv.FormatFloat(value, 'f', 1, 64))
 	}
-	o.AveragePeriod, err = strconv.ParseFloat(datafield[10], 64)
-	if err != nil {
-		log.Fatal("o.AveragePeriod:", err)
-	}
-	o.MeanWaveDirection = wavecardinal
-	o.WaterTemperature = watertempF
-
-	return o
-}
-
-// Given Observation, returns formatted text for tweet
-func formatObservation(o Observation) string {
-	output := fmt.Sprint(o.Date.Format(time.RFC822), "\nSwell: ", strconv.FormatFloat(float64(o.SignificantWaveHeight), 'f', 1, 64), "ft at ", o.DominantWavePeriod, " sec from ", o.MeanWaveDirection, "\n", "Water: ", o.WaterTemperature, "F")
 	return output
 }
 
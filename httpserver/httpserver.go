@@ -0,0 +1,199 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+// Package httpserver exposes BuoyBot's stored observations over HTTP as
+// JSON or CSV, plus a Prometheus /metrics endpoint, so the same binary can
+// power dashboards and integrations in addition to tweeting.
+package httpserver
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server serves BuoyBot's observation history over HTTP.
+type Server struct {
+	db      *sql.DB
+	metrics *Metrics
+}
+
+// NewServer builds a Server reading from db and exporting metrics.
+func NewServer(db *sql.DB, metrics *Metrics) *Server {
+	return &Server{db: db, metrics: metrics}
+}
+
+// Handler returns the Server's routes, ready to pass to http.ListenAndServe.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/observations/", s.handleObservations)
+	mux.Handle("/metrics", promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// handleObservations dispatches GET /observations/{buoyId}/latest and
+// GET /observations/{buoyId}?since=&until=&format=csv|json.
+func (s *Server) handleObservations(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/observations/")
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		http.Error(w, "missing buoy id", http.StatusBadRequest)
+		return
+	}
+
+	if buoyID, ok := strings.CutSuffix(path, "/latest"); ok {
+		s.handleLatest(w, r, buoyID)
+		return
+	}
+	s.handleRange(w, r, path)
+}
+
+// handleLatest writes the most recent observation for buoyID as JSON.
+func (s *Server) handleLatest(w http.ResponseWriter, r *http.Request, buoyID string) {
+	var observedAt time.Time
+	err := s.db.QueryRowContext(r.Context(), "SELECT observationtime FROM observations WHERE buoyid = $1 ORDER BY observationtime DESC LIMIT 1", buoyID).Scan(&observedAt)
+	if err == sql.ErrNoRows {
+		http.Error(w, "no observations for buoy "+buoyID, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := s.db.QueryContext(r.Context(), "SELECT sensor, value FROM observations WHERE buoyid = $1 AND observationtime = $2", buoyID, observedAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	obs := jsonObservation{StationID: buoyID, Date: observedAt, Sensors: map[string]float64{}}
+	for rows.Next() {
+		var sensor string
+		var value float64
+		if err := rows.Scan(&sensor, &value); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		obs.Sensors[sensor] = value
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(obs)
+}
+
+// handleRange writes observations for buoyID between the since/until query
+// parameters (RFC3339 timestamps; until defaults to now, since defaults to
+// 24 hours before until) as CSV or JSON depending on the format parameter.
+func (s *Server) handleRange(w http.ResponseWriter, r *http.Request, buoyID string) {
+	until := time.Now()
+	if v := r.URL.Query().Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "invalid until: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		until = t
+	}
+	since := until.Add(-24 * time.Hour)
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	rows, err := s.db.QueryContext(r.Context(), "SELECT observationtime, sensor, value FROM observations WHERE buoyid = $1 AND observationtime BETWEEN $2 AND $3 ORDER BY observationtime ASC", buoyID, since, until)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	format := r.URL.Query().Get("format")
+	if format == "csv" {
+		s.writeCSV(w, rows)
+		return
+	}
+	s.writeJSON(w, buoyID, rows)
+}
+
+// writeCSV streams rows directly to w as they're read from the cursor,
+// without buffering the result set in memory.
+func (s *Server) writeCSV(w http.ResponseWriter, rows *sql.Rows) {
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "sensor", "value"})
+
+	for rows.Next() {
+		var t time.Time
+		var sensor string
+		var value float64
+		if err := rows.Scan(&t, &sensor, &value); err != nil {
+			fmt.Fprintln(w, "error reading row:", err)
+			return
+		}
+		cw.Write([]string{t.Format(time.RFC3339), sensor, strconv.FormatFloat(value, 'f', -1, 64)})
+		cw.Flush()
+	}
+}
+
+// jsonObservation is the wire shape for a normalized observation; it's
+// defined here rather than reusing sources.Observation so the HTTP API
+// stays stable even if the internal sensor map type changes.
+type jsonObservation struct {
+	StationID string             `json:"stationId"`
+	Date      time.Time          `json:"date"`
+	Sensors   map[string]float64 `json:"sensors"`
+}
+
+// writeJSON groups rows by observation time into normalized Observations
+// and writes them as a JSON array.
+func (s *Server) writeJSON(w http.ResponseWriter, buoyID string, rows *sql.Rows) {
+	byTime := map[time.Time]*jsonObservation{}
+	var order []time.Time
+
+	for rows.Next() {
+		var t time.Time
+		var sensor string
+		var value float64
+		if err := rows.Scan(&t, &sensor, &value); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		obs, ok := byTime[t]
+		if !ok {
+			obs = &jsonObservation{StationID: buoyID, Date: t, Sensors: map[string]float64{}}
+			byTime[t] = obs
+			order = append(order, t)
+		}
+		obs.Sensors[sensor] = value
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]*jsonObservation, 0, len(order))
+	for _, t := range order {
+		result = append(result, byTime[t])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+package httpserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/vlazzle/BuoyBot/sources"
+)
+
+// Metrics holds the Prometheus collectors BuoyBot exports, independent of
+// whether the HTTP server that serves /metrics is running the fetch/tweet
+// loop itself.
+type Metrics struct {
+	WaveHeightFeet *prometheus.GaugeVec
+	FetchSuccesses prometheus.Counter
+	FetchFailures  prometheus.Counter
+	SaveFailures   prometheus.Counter
+	TweetsPosted   prometheus.Counter
+
+	registry *prometheus.Registry
+}
+
+// NewMetrics creates and registers BuoyBot's Prometheus collectors.
+func NewMetrics() *Metrics {
+	registry := prometheus.NewRegistry()
+
+	m := &Metrics{
+		WaveHeightFeet: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "buoybot_wave_height_feet",
+			Help: "Most recently observed significant wave height, in feet.",
+		}, []string{"buoy"}),
+		FetchSuccesses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "buoybot_fetch_successes_total",
+			Help: "Number of observations successfully fetched from a data source.",
+		}),
+		FetchFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "buoybot_fetch_failures_total",
+			Help: "Number of observation fetches that failed.",
+		}),
+		SaveFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "buoybot_save_failures_total",
+			Help: "Number of observations that failed to persist to a storage backend.",
+		}),
+		TweetsPosted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "buoybot_tweets_posted_total",
+			Help: "Number of observation tweets successfully posted.",
+		}),
+		registry: registry,
+	}
+
+	registry.MustRegister(m.WaveHeightFeet, m.FetchSuccesses, m.FetchFailures, m.SaveFailures, m.TweetsPosted)
+	return m
+}
+
+// RecordObservation updates gauges from a freshly fetched Observation.
+func (m *Metrics) RecordObservation(o sources.Observation) {
+	if v, ok := o.Value(sources.SensorWaveHeightFt); ok {
+		m.WaveHeightFeet.WithLabelValues(o.StationID).Set(v)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+// Package scheduler drives BuoyBot's recurring jobs (fetch, tweet, digest)
+// from per-station cron specs, replacing the old "only run at these hours"
+// gate baked into main().
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler runs named jobs on cron schedules, each in its own time zone,
+// never letting two runs of the same job overlap.
+type Scheduler struct {
+	cron *cron.Cron
+}
+
+// New creates a Scheduler. Job-level locations are set per AddJob call, so
+// the Scheduler itself runs in UTC.
+func New() *Scheduler {
+	return &Scheduler{
+		cron: cron.New(cron.WithLocation(time.UTC)),
+	}
+}
+
+// AddJob registers fn to run on spec (standard 5-field cron syntax,
+// interpreted in loc) under name. Overlapping runs of the same job are
+// skipped rather than queued.
+func (s *Scheduler) AddJob(name, spec string, loc *time.Location, fn func()) error {
+	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	schedule, err := parser.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("scheduler: invalid cron spec %q for job %q: %w", spec, name, err)
+	}
+	localized := cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger)).Then(cron.FuncJob(fn))
+	s.cron.Schedule(inLocation{schedule, loc}, wrappedJob{name, localized})
+	return nil
+}
+
+// Start begins running scheduled jobs in the background.
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop waits for any running jobs to finish and stops scheduling new ones.
+// It blocks until ctx-equivalent cron internal context is done.
+func (s *Scheduler) Stop() {
+	ctx := s.cron.Stop()
+	<-ctx.Done()
+}
+
+// inLocation adapts a cron.Schedule to evaluate Next() in loc, so each
+// station's schedule can run in its own local time regardless of the
+// Scheduler's own (UTC) location.
+type inLocation struct {
+	cron.Schedule
+	loc *time.Location
+}
+
+func (s inLocation) Next(t time.Time) time.Time {
+	return s.Schedule.Next(t.In(s.loc)).In(t.Location())
+}
+
+// wrappedJob logs when a named job starts so overlapping runs (and their
+// skips) are visible in the logs.
+type wrappedJob struct {
+	name string
+	job  cron.Job
+}
+
+func (w wrappedJob) Run() {
+	log.Printf("scheduler: running job %q", w.name)
+	w.job.Run()
+}
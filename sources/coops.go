@@ -0,0 +1,69 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// URL format for CO-OPS tides and currents JSON data. Product/units/time
+// zone are fixed to the latest observed water level in feet, local time.
+const coopsURLFmt = "https://api.tidesandcurrents.noaa.gov/api/prod/datagetter?station=%s&product=water_level&date=latest&datum=MLLW&units=english&time_zone=lst_ldt&format=json"
+
+// CoopsSource fetches observed water level from NOAA CO-OPS tides and
+// currents stations.
+type CoopsSource struct{}
+
+func init() {
+	Register(CoopsSource{})
+}
+
+// Name implements Source.
+func (CoopsSource) Name() string { return "coops-tides" }
+
+type coopsResponse struct {
+	Data []struct {
+		Time  string `json:"t"`
+		Value string `json:"v"`
+	} `json:"data"`
+}
+
+// Fetch implements Source.
+func (s CoopsSource) Fetch(stationID string) (Observation, error) {
+	url := fmt.Sprintf(coopsURLFmt, stationID)
+	raw, err := getDataFromURL(url)
+	if err != nil {
+		return Observation{}, err
+	}
+
+	var resp coopsResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return Observation{}, fmt.Errorf("coops-tides: decoding response: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return Observation{}, fmt.Errorf("coops-tides: no data returned for station %s", stationID)
+	}
+	reading := resp.Data[len(resp.Data)-1]
+
+	tide, err := strconv.ParseFloat(reading.Value, 64)
+	if err != nil {
+		return Observation{}, fmt.Errorf("coops-tides: parsing water level: %w", err)
+	}
+	t, err := time.Parse("2006-01-02 15:04", reading.Time)
+	if err != nil {
+		return Observation{}, fmt.Errorf("coops-tides: parsing observation time: %w", err)
+	}
+
+	return Observation{
+		StationID: stationID,
+		Date:      t,
+		Sensors: map[string]float64{
+			SensorTideFt: tide,
+		},
+	}, nil
+}
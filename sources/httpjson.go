@@ -0,0 +1,71 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HTTPJSONSource fetches a single JSON document from an arbitrary URL and
+// extracts requested metrics via simple top-level field lookups. It's the
+// escape hatch for providers that don't warrant a dedicated adapter.
+//
+// Configure per-station with a URLFmt containing a single "%s" for the
+// station ID, and a FieldMap translating sensor names (e.g. "wave_height_ft")
+// to the JSON field holding that value. Unlike the other adapters it isn't
+// self-registering: it needs per-station configuration, so callers read it
+// straight out of StationConfig.HTTPJSON instead of going through
+// sources.Get.
+type HTTPJSONSource struct {
+	URLFmt   string            `json:"URLFmt"`
+	TimeKey  string            `json:"TimeKey"`
+	FieldMap map[string]string `json:"FieldMap"`
+}
+
+// NewHTTPJSONSource constructs an HTTPJSONSource adapter.
+func NewHTTPJSONSource(urlFmt, timeKey string, fieldMap map[string]string) HTTPJSONSource {
+	return HTTPJSONSource{URLFmt: urlFmt, TimeKey: timeKey, FieldMap: fieldMap}
+}
+
+// Name implements Source.
+func (HTTPJSONSource) Name() string { return "http-json" }
+
+// Fetch implements Source.
+func (s HTTPJSONSource) Fetch(stationID string) (Observation, error) {
+	url := fmt.Sprintf(s.URLFmt, stationID)
+	raw, err := getDataFromURL(url)
+	if err != nil {
+		return Observation{}, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return Observation{}, fmt.Errorf("http-json: decoding response: %w", err)
+	}
+
+	o := Observation{StationID: stationID, Date: time.Now(), Sensors: map[string]float64{}}
+	if s.TimeKey != "" {
+		if raw, ok := doc[s.TimeKey].(string); ok {
+			if t, err := time.Parse(time.RFC3339, raw); err == nil {
+				o.Date = t
+			}
+		}
+	}
+
+	for sensor, field := range s.FieldMap {
+		v, ok := doc[field]
+		if !ok {
+			continue
+		}
+		f, ok := v.(float64)
+		if !ok {
+			continue
+		}
+		o.Sensors[sensor] = f
+	}
+	return o, nil
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URL format for NDBC realtime2 text observations
+const ndbcRealtimeURLFmt = "https://www.ndbc.noaa.gov/data/realtime2/%s.txt"
+
+// missingValue is NDBC's sentinel for a field with no reading.
+const missingValue = "MM"
+
+// ndbcColumn maps an NDBC realtime2 header name to the sensor key it
+// should populate, and the unit conversion to apply to a parsed value.
+// Columns not listed here (WDIR, PRES, DEWP, VIS, PTDY, TIDE, ...) are
+// ignored for now.
+var ndbcColumn = map[string]struct {
+	sensor  string
+	convert func(float64) float64
+}{
+	"WVHT": {SensorWaveHeightFt, metersToFeet},
+	"DPD":  {SensorDominantPeriodSec, identity},
+	"APD":  {SensorAveragePeriodSec, identity},
+	"MWD":  {SensorMeanWaveDirectionDeg, identity},
+	"WTMP": {SensorWaterTempF, celsiusToFahrenheit},
+	"WSPD": {SensorWindSpeedKt, metersPerSecondToKnots},
+	"GST":  {SensorGustSpeedKt, metersPerSecondToKnots},
+}
+
+func identity(v float64) float64               { return v }
+func metersToFeet(m float64) float64           { return m * 3.28084 }
+func celsiusToFahrenheit(c float64) float64    { return c*9/5 + 32 }
+func metersPerSecondToKnots(v float64) float64 { return v * 1.943844 }
+
+// NDBCRealtimeSource fetches standard meteorological observations from
+// NDBC's realtime2 .txt feed (wave height/period, direction, water temp).
+type NDBCRealtimeSource struct{}
+
+func init() {
+	Register(NDBCRealtimeSource{})
+}
+
+// Name implements Source.
+func (NDBCRealtimeSource) Name() string { return "ndbc-realtime2" }
+
+// Fetch implements Source.
+func (s NDBCRealtimeSource) Fetch(stationID string) (Observation, error) {
+	url := fmt.Sprintf(ndbcRealtimeURLFmt, stationID)
+	raw, err := getDataFromURL(url)
+	if err != nil {
+		return Observation{}, err
+	}
+
+	observations, err := ParseNDBCRealtime(bytes.NewReader(raw))
+	if err != nil {
+		return Observation{}, err
+	}
+	if len(observations) == 0 {
+		return Observation{}, fmt.Errorf("ndbc-realtime2: no usable rows for station %s", stationID)
+	}
+
+	o := observations[0]
+	o.StationID = stationID
+	return o, nil
+}
+
+// Given URL, returns the raw response body
+func getDataFromURL(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching data: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	return body, nil
+}
+
+// ParseNDBCRealtime parses an NDBC realtime2 .txt payload (the "#"-prefixed
+// column-name and units header followed by one row per observation, most
+// recent first) into a slice of Observations, one per usable data row.
+// Rows where every recognized column is the "MM" missing-value sentinel
+// are skipped. Unlike the byte-offset parsing this replaces, this keys off
+// the header's column names, so it tolerates NDBC adding, removing, or
+// reordering columns.
+func ParseNDBCRealtime(r io.Reader) ([]Observation, error) {
+	scanner := bufio.NewScanner(r)
+
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("ndbc-realtime2: empty payload")
+	}
+	header := strings.Fields(strings.TrimPrefix(scanner.Text(), "#"))
+	colIndex := make(map[string]int, len(header))
+	for i, name := range header {
+		colIndex[name] = i
+	}
+
+	// Second header line holds units; not needed since conversions are
+	// fixed per column, but it must be consumed so it isn't parsed as data.
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("ndbc-realtime2: missing units header line")
+	}
+
+	timeIdx, haveTimeIdx := columnIndices(colIndex, "YY", "MM", "DD", "hh", "mm")
+
+	var observations []Observation
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < len(header) {
+			continue
+		}
+
+		o := Observation{Sensors: map[string]float64{}}
+		for name, col := range ndbcColumn {
+			idx, ok := colIndex[name]
+			if !ok || idx >= len(fields) {
+				continue
+			}
+			raw := fields[idx]
+			if raw == missingValue {
+				continue
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			o.Sensors[col.sensor] = col.convert(v)
+		}
+		if len(o.Sensors) == 0 {
+			// Every recognized column was missing - nothing to report.
+			continue
+		}
+
+		if haveTimeIdx {
+			if t, ok := parseObservationTime(fields, timeIdx); ok {
+				o.Date = t
+			}
+		}
+		observations = append(observations, o)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ndbc-realtime2: reading payload: %w", err)
+	}
+
+	return observations, nil
+}
+
+// columnIndices looks up each of names in colIndex, returning the indices
+// in order and true only if every name was found - callers must not treat
+// a partial result as usable, since a missing header column would
+// otherwise silently resolve to index 0.
+func columnIndices(colIndex map[string]int, names ...string) ([]int, bool) {
+	idx := make([]int, len(names))
+	for i, name := range names {
+		col, ok := colIndex[name]
+		if !ok {
+			return nil, false
+		}
+		idx[i] = col
+	}
+	return idx, true
+}
+
+// parseObservationTime builds a time.Time from the YY MM DD hh mm columns
+// at the given indices. NDBC's realtime2 feed reports these in UTC for
+// every station, so the result is left in UTC rather than localized to any
+// one station's time zone - callers that display a time should localize it
+// to the station they fetched it for.
+func parseObservationTime(fields []string, idx []int) (time.Time, bool) {
+	for _, i := range idx {
+		if i < 0 || i >= len(fields) {
+			return time.Time{}, false
+		}
+	}
+	rawtime := strings.Join([]string{fields[idx[0]], fields[idx[1]], fields[idx[2]], fields[idx[3]], fields[idx[4]]}, " ")
+	t, err := time.Parse("2006 01 02 15 04", rawtime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
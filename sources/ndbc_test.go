@@ -0,0 +1,147 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+package sources
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseNDBCRealtime(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		wantRows    int
+		wantHeight0 float64
+	}{
+		{
+			name:        "realtime2 feed with a fully-missing row",
+			fixture:     "testdata/realtime2_46026.txt",
+			wantRows:    4,
+			wantHeight0: 1.20 * 3.28084,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open(tc.fixture)
+			if err != nil {
+				t.Fatalf("opening fixture: %v", err)
+			}
+			defer f.Close()
+
+			observations, err := ParseNDBCRealtime(f)
+			if err != nil {
+				t.Fatalf("ParseNDBCRealtime: %v", err)
+			}
+			if len(observations) != tc.wantRows {
+				t.Fatalf("got %d observations, want %d", len(observations), tc.wantRows)
+			}
+
+			height, ok := observations[0].Value(SensorWaveHeightFt)
+			if !ok {
+				t.Fatalf("observations[0] missing %s", SensorWaveHeightFt)
+			}
+			if diff := height - tc.wantHeight0; diff > 0.0001 || diff < -0.0001 {
+				t.Errorf("observations[0] wave height = %v, want %v", height, tc.wantHeight0)
+			}
+		})
+	}
+}
+
+func TestParseNDBCRealtimeSkipsFullyMissingRows(t *testing.T) {
+	f, err := os.Open("testdata/realtime2_46026.txt")
+	if err != nil {
+		t.Fatalf("opening fixture: %v", err)
+	}
+	defer f.Close()
+
+	observations, err := ParseNDBCRealtime(f)
+	if err != nil {
+		t.Fatalf("ParseNDBCRealtime: %v", err)
+	}
+	for _, o := range observations {
+		if len(o.Sensors) == 0 {
+			t.Errorf("got observation with no sensors, want fully-missing rows to be skipped")
+		}
+	}
+}
+
+func TestParseNDBCRealtimeRejectsEmptyPayload(t *testing.T) {
+	if _, err := ParseNDBCRealtime(strings.NewReader("")); err == nil {
+		t.Errorf("expected error for empty payload, got nil")
+	}
+}
+
+// TestParseNDBCRealtimePartialMissingRow exercises the 12:35 fixture row,
+// which has some but not all recognized sensor columns set to the "MM"
+// missing-value sentinel: it must come through with exactly the present
+// sensors populated, not be dropped (as a fully-missing row would be) and
+// not have MM misparsed as a value.
+func TestParseNDBCRealtimePartialMissingRow(t *testing.T) {
+	f, err := os.Open("testdata/realtime2_46026.txt")
+	if err != nil {
+		t.Fatalf("opening fixture: %v", err)
+	}
+	defer f.Close()
+
+	observations, err := ParseNDBCRealtime(f)
+	if err != nil {
+		t.Fatalf("ParseNDBCRealtime: %v", err)
+	}
+
+	// The 12:35 row is the third data row in the fixture (after 12:50, 12:40).
+	if len(observations) < 3 {
+		t.Fatalf("got %d observations, want at least 3", len(observations))
+	}
+	partial := observations[2]
+
+	want := map[string]float64{
+		SensorGustSpeedKt:          5.5 * 1.943844,
+		SensorWaveHeightFt:         1.15 * 3.28084,
+		SensorAveragePeriodSec:     7.0,
+		SensorMeanWaveDirectionDeg: 278,
+	}
+	if len(partial.Sensors) != len(want) {
+		t.Fatalf("got %d sensors %v, want %d sensors %v", len(partial.Sensors), partial.Sensors, len(want), want)
+	}
+	for sensor, wantValue := range want {
+		got, ok := partial.Value(sensor)
+		if !ok {
+			t.Errorf("missing sensor %s", sensor)
+			continue
+		}
+		if diff := got - wantValue; diff > 0.0001 || diff < -0.0001 {
+			t.Errorf("sensor %s = %v, want %v", sensor, got, wantValue)
+		}
+	}
+	for _, missing := range []string{SensorWindSpeedKt, SensorDominantPeriodSec, SensorWaterTempF} {
+		if _, ok := partial.Value(missing); ok {
+			t.Errorf("sensor %s should be missing (MM in fixture), but was present", missing)
+		}
+	}
+}
+
+// TestParseNDBCRealtimeMissingHeaderColumn guards against a header that's
+// missing one of the date/time columns silently resolving to column 0 (the
+// zero value of a plain map lookup) instead of being treated as "not
+// found" - the exact corruption this parser was written to eliminate.
+func TestParseNDBCRealtimeMissingHeaderColumn(t *testing.T) {
+	payload := "#YY  DD hh mm WVHT\n" +
+		"#yr  dy hr mn    m\n" +
+		"2024 01 12 50  1.20\n"
+
+	observations, err := ParseNDBCRealtime(strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("ParseNDBCRealtime: %v", err)
+	}
+	if len(observations) != 1 {
+		t.Fatalf("got %d observations, want 1", len(observations))
+	}
+	if !observations[0].Date.IsZero() {
+		t.Errorf("Date = %v, want zero value when the MM header column is absent", observations[0].Date)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+package sources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URL format for NDBC spectral wave summary data
+const ndbcSpecURLFmt = "https://www.ndbc.noaa.gov/data/realtime2/%s.spec"
+
+// NDBCSpecSource fetches spectral wave summary data (.spec) from NDBC,
+// which carries swell/wind-sea separation and spectral peak period that
+// the realtime2 feed doesn't report.
+type NDBCSpecSource struct{}
+
+func init() {
+	Register(NDBCSpecSource{})
+}
+
+// Name implements Source.
+func (NDBCSpecSource) Name() string { return "ndbc-spec" }
+
+// Fetch implements Source.
+func (s NDBCSpecSource) Fetch(stationID string) (Observation, error) {
+	url := fmt.Sprintf(ndbcSpecURLFmt, stationID)
+	raw, err := getDataFromURL(url)
+	if err != nil {
+		return Observation{}, err
+	}
+	return parseNDBCSpec(stationID, raw)
+}
+
+// parseNDBCSpec extracts the most recent record from a .spec payload. The
+// column layout is: YY MM DD hh mm WVHT SwH SwP WWH WWP SwD WWD STEEPNESS APD MWD
+func parseNDBCSpec(stationID string, d []byte) (Observation, error) {
+	lines := strings.Split(strings.TrimSpace(string(d)), "\n")
+	if len(lines) < 3 {
+		return Observation{}, fmt.Errorf("ndbc-spec: expected header and at least one data row, got %d lines", len(lines))
+	}
+	fields := strings.Fields(lines[2])
+	if len(fields) < 14 {
+		return Observation{}, fmt.Errorf("ndbc-spec: expected at least 14 columns, got %d", len(fields))
+	}
+
+	waveheightmeters, _ := strconv.ParseFloat(fields[5], 64)
+	waveheightfeet := waveheightmeters * 3.28084
+
+	spectralPeakPeriod, err := strconv.ParseFloat(fields[7], 64)
+	if err != nil {
+		return Observation{}, fmt.Errorf("parsing spectral peak period: %w", err)
+	}
+	averagePeriod, _ := strconv.ParseFloat(fields[13], 64)
+
+	rawtime := strings.Join(fields[0:5], " ")
+	t, err := time.Parse("2006 01 02 15 04", rawtime)
+	if err != nil {
+		return Observation{}, fmt.Errorf("parsing observation time: %w", err)
+	}
+
+	return Observation{
+		StationID: stationID,
+		Date:      t,
+		Sensors: map[string]float64{
+			SensorWaveHeightFt:       waveheightfeet,
+			SensorSpectralPeakPeriod: spectralPeakPeriod,
+			SensorAveragePeriodSec:   averagePeriod,
+		},
+	}, nil
+}
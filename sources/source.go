@@ -0,0 +1,94 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+// Package sources implements pluggable adapters that fetch observations
+// from ocean/weather data providers (NDBC, CO-OPS, generic HTTP JSON, ...)
+// and normalize them into a common Observation shape.
+package sources
+
+import (
+	"fmt"
+	"time"
+)
+
+// Well-known sensor keys. Adapters are not limited to these - a source may
+// populate any key it likes - but formatting/storage code recognizes these
+// names when present.
+const (
+	SensorWaveHeightFt         = "wave_height_ft"
+	SensorDominantPeriodSec    = "dominant_period_sec"
+	SensorAveragePeriodSec     = "average_period_sec"
+	SensorMeanWaveDirectionDeg = "mean_wave_direction_deg"
+	SensorWaterTempF           = "water_temp_f"
+	SensorWindSpeedKt          = "wind_speed_kt"
+	SensorGustSpeedKt          = "gust_speed_kt"
+	SensorTideFt               = "tide_ft"
+	SensorSpectralPeakPeriod   = "spectral_peak_period_sec"
+)
+
+// Observation stores a normalized reading from a data source. Sensors holds
+// whatever metrics the source adapter was able to extract, keyed by one of
+// the Sensor* constants above (or an adapter-specific name), so callers
+// aren't limited to a fixed set of fields.
+type Observation struct {
+	StationID string
+	Date      time.Time
+	Sensors   map[string]float64
+}
+
+// Value returns the named sensor reading and whether it was present.
+func (o Observation) Value(name string) (float64, bool) {
+	v, ok := o.Sensors[name]
+	return v, ok
+}
+
+// Filter returns a copy of o with only the named sensors kept, so a
+// station configured for e.g. just wave height doesn't get tide or wind
+// fields it never asked for. An empty metrics list means "no filtering";
+// o is returned unchanged.
+func (o Observation) Filter(metrics []string) Observation {
+	if len(metrics) == 0 {
+		return o
+	}
+	keep := make(map[string]bool, len(metrics))
+	for _, m := range metrics {
+		keep[m] = true
+	}
+	filtered := make(map[string]float64, len(o.Sensors))
+	for sensor, value := range o.Sensors {
+		if keep[sensor] {
+			filtered[sensor] = value
+		}
+	}
+	o.Sensors = filtered
+	return o
+}
+
+// Source fetches and normalizes observations for a station from a single
+// provider. Implementations live alongside this file, one per provider.
+// Filtering to the metrics a station actually wants is the caller's job
+// (see Observation.Filter), not the adapter's.
+type Source interface {
+	// Name identifies the adapter, e.g. "ndbc-realtime2".
+	Name() string
+	// Fetch retrieves the latest observation for stationID.
+	Fetch(stationID string) (Observation, error)
+}
+
+var registry = map[string]Source{}
+
+// Register makes a Source available under its Name() for use by config.
+// Adapters call this from an init() func.
+func Register(s Source) {
+	registry[s.Name()] = s
+}
+
+// Get looks up a registered Source by name.
+func Get(name string) (Source, error) {
+	s, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("sources: no source registered with name %q", name)
+	}
+	return s, nil
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+// Package stats computes short-term climatology (mean, spread, trend) from
+// BuoyBot's stored observation history, so tweets can say whether swell is
+// building or dropping rather than just reporting a single point in time.
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// MinSamples is the fewest rows ComputeSwellTrend will compute a trend from.
+// Below this, a slope is too noisy to be worth reporting.
+const MinSamples = 5
+
+// buildingThreshold is the slope (feet per hour) above which swell is
+// reported as building or dropping rather than steady.
+const buildingThreshold = 0.02
+
+// SwellTrend summarizes significant wave height and dominant period over a
+// lookback window.
+type SwellTrend struct {
+	Lookback           time.Duration
+	Samples            int
+	HeightMean         float64
+	HeightStdDev       float64
+	PeriodSamples      int
+	PeriodP25          float64
+	PeriodMedian       float64
+	PeriodP75          float64
+	HeightSlopePerHour float64
+}
+
+// Direction describes whether swell is building, dropping, or holding
+// steady based on the height regression slope.
+func (t SwellTrend) Direction() string {
+	switch {
+	case t.HeightSlopePerHour > buildingThreshold:
+		return "building"
+	case t.HeightSlopePerHour < -buildingThreshold:
+		return "dropping"
+	default:
+		return "steady"
+	}
+}
+
+// arrow returns a compact glyph for Direction, for use in tweet text.
+func (t SwellTrend) arrow() string {
+	switch t.Direction() {
+	case "building":
+		return "↑"
+	case "dropping":
+		return "↓"
+	default:
+		return "→"
+	}
+}
+
+// String renders a compact trend line, e.g. "24h: μ=4.1ft σ=0.8 ↑building
+// period 9-12s (p50=10)". The period clause is omitted when there weren't
+// any dominant-period rows to compute it from.
+func (t SwellTrend) String() string {
+	hours := int(t.Lookback.Hours())
+	line := fmt.Sprintf("%dh: μ=%.1fft σ=%.1f %s%s", hours, t.HeightMean, t.HeightStdDev, t.arrow(), t.Direction())
+	if t.PeriodSamples > 0 {
+		line += fmt.Sprintf(" period %.0f-%.0fs (p50=%.0f)", t.PeriodP25, t.PeriodP75, t.PeriodMedian)
+	}
+	return line
+}
+
+// ComputeSwellTrend queries the last `lookback` worth of wave_height_ft and
+// dominant_period_sec rows for stationID and summarizes them. The second
+// return value is false (with a zero SwellTrend) when there aren't enough
+// rows to compute a meaningful trend - callers should skip appending a
+// trend line rather than report one from too little data.
+func ComputeSwellTrend(ctx context.Context, db *sql.DB, stationID string, lookback time.Duration) (SwellTrend, bool, error) {
+	since := time.Now().Add(-lookback)
+
+	heights, heightTimes, err := querySensorSeries(ctx, db, stationID, "wave_height_ft", since)
+	if err != nil {
+		return SwellTrend{}, false, fmt.Errorf("stats: querying wave height history: %w", err)
+	}
+	if len(heights) < MinSamples {
+		return SwellTrend{}, false, nil
+	}
+
+	periods, _, err := querySensorSeries(ctx, db, stationID, "dominant_period_sec", since)
+	if err != nil {
+		return SwellTrend{}, false, fmt.Errorf("stats: querying dominant period history: %w", err)
+	}
+
+	mean, stddev := stat.MeanStdDev(heights, nil)
+
+	xs := make([]float64, len(heightTimes))
+	for i, ts := range heightTimes {
+		xs[i] = ts.Sub(heightTimes[0]).Hours()
+	}
+	_, slope := stat.LinearRegression(xs, heights, nil, false)
+
+	var periodP25, periodMedian, periodP75 float64
+	if len(periods) > 0 {
+		sorted := append([]float64(nil), periods...)
+		stat.SortWeighted(sorted, nil)
+		periodP25 = stat.Quantile(0.25, stat.Empirical, sorted, nil)
+		periodMedian = stat.Quantile(0.5, stat.Empirical, sorted, nil)
+		periodP75 = stat.Quantile(0.75, stat.Empirical, sorted, nil)
+	}
+
+	return SwellTrend{
+		Lookback:           lookback,
+		Samples:            len(heights),
+		HeightMean:         mean,
+		HeightStdDev:       stddev,
+		PeriodSamples:      len(periods),
+		PeriodP25:          periodP25,
+		PeriodMedian:       periodMedian,
+		PeriodP75:          periodP75,
+		HeightSlopePerHour: slope,
+	}, true, nil
+}
+
+// querySensorSeries returns a sensor's values and observation times since
+// the given time, ordered oldest first.
+func querySensorSeries(ctx context.Context, db *sql.DB, stationID, sensor string, since time.Time) ([]float64, []time.Time, error) {
+	rows, err := db.QueryContext(ctx, "SELECT value, observationtime FROM observations WHERE buoyid = $1 AND sensor = $2 AND observationtime > $3 ORDER BY observationtime ASC", stationID, sensor, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var values []float64
+	var times []time.Time
+	for rows.Next() {
+		var v float64
+		var t time.Time
+		if err := rows.Scan(&v, &t); err != nil {
+			return nil, nil, err
+		}
+		values = append(values, v)
+		times = append(times, t)
+	}
+	return values, times, rows.Err()
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	influxdb3 "github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+
+	"github.com/vlazzle/BuoyBot/sources"
+)
+
+// ClientConfig holds the connection settings for the InfluxDB v3 writer,
+// sourced from INFLUX_URL/INFLUX_TOKEN/INFLUX_DATABASE env vars or the
+// equivalent block in config.json.
+type ClientConfig struct {
+	URL      string `json:"URL"`
+	Token    string `json:"Token"`
+	Database string `json:"Database"`
+}
+
+// InfluxWriter writes observations to InfluxDB v3 as points tagged by
+// buoy_id, one point per observation with a field per sensor.
+type InfluxWriter struct {
+	client *influxdb3.Client
+}
+
+// NewInfluxWriter opens a client for the given config. Callers should
+// Close the returned writer's client via Close when done.
+func NewInfluxWriter(cfg ClientConfig) (*InfluxWriter, error) {
+	client, err := influxdb3.New(influxdb3.ClientConfig{
+		Host:     cfg.URL,
+		Token:    cfg.Token,
+		Database: cfg.Database,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: opening influxdb3 client: %w", err)
+	}
+	return &InfluxWriter{client: client}, nil
+}
+
+// Close releases the underlying client's resources.
+func (w *InfluxWriter) Close() error {
+	return w.client.Close()
+}
+
+// Write implements Writer. Every sensor on the observation becomes a field
+// on a single "observation" point tagged by buoy_id, so a batch write
+// covers the whole reading in one round trip.
+func (w *InfluxWriter) Write(ctx context.Context, o sources.Observation) error {
+	fields := make(map[string]interface{}, len(o.Sensors))
+	for sensor, value := range o.Sensors {
+		fields[sensor] = value
+	}
+
+	point := influxdb3.NewPoint(
+		"observation",
+		map[string]string{"buoy_id": o.StationID},
+		fields,
+		o.Date,
+	)
+
+	if err := w.client.WritePoints(ctx, []*influxdb3.Point{point}); err != nil {
+		return fmt.Errorf("storage: influxdb3 write failed for station %s: %w", o.StationID, err)
+	}
+	return nil
+}
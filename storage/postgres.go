@@ -0,0 +1,43 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/vlazzle/BuoyBot/sources"
+)
+
+// PostgresWriter persists observations as one row per sensor, into an
+// EAV-style "observations" table: (buoyid, observationtime, sensor, value).
+//
+// This replaced BuoyBot's original fixed-column, single-buoy table (one row
+// per observation with a column per sensor, no buoyid at all). The two
+// schemas aren't compatible: an existing deployment's table must be
+// recreated with the buoyid/observationtime/sensor/value shape above before
+// upgrading, since there's no migrations tooling in this repo to do it
+// automatically. Writes against the old table will fail with a missing-
+// column error until that's done by hand.
+type PostgresWriter struct {
+	DB *sql.DB
+}
+
+// NewPostgresWriter wraps an existing *sql.DB as a Writer.
+func NewPostgresWriter(db *sql.DB) PostgresWriter {
+	return PostgresWriter{DB: db}
+}
+
+// Write implements Writer.
+func (w PostgresWriter) Write(ctx context.Context, o sources.Observation) error {
+	for sensor, value := range o.Sensors {
+		_, err := w.DB.ExecContext(ctx, "INSERT INTO observations(buoyid, observationtime, sensor, value) VALUES($1, $2, $3, $4)", o.StationID, o.Date, sensor, value)
+		if err != nil {
+			return fmt.Errorf("storage: postgres write failed for station %s sensor %s: %w", o.StationID, sensor, err)
+		}
+	}
+	return nil
+}
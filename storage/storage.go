@@ -0,0 +1,36 @@
+// Copyright (c) 2016 John Beil.
+// Use of this source code is governed by the MIT License.
+// The MIT license can be found in the LICENSE file.
+
+// Package storage persists observations to one or more time-series
+// backends behind a common Writer interface.
+package storage
+
+import (
+	"context"
+
+	"github.com/vlazzle/BuoyBot/sources"
+)
+
+// Writer persists a single Observation. Implementations should treat
+// Write as idempotent-ish (callers may retry) but are not required to
+// deduplicate.
+type Writer interface {
+	Write(ctx context.Context, o sources.Observation) error
+}
+
+// MultiWriter fans an Observation out to every wrapped Writer, continuing
+// on error so one backend being down doesn't block the others, and
+// returning the first error encountered (if any) after all writers run.
+type MultiWriter []Writer
+
+// Write implements Writer.
+func (m MultiWriter) Write(ctx context.Context, o sources.Observation) error {
+	var firstErr error
+	for _, w := range m {
+		if err := w.Write(ctx, o); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}